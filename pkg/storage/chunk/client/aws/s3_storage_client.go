@@ -0,0 +1,697 @@
+package aws
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	awsCommon "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/grafana/dskit/backoff"
+	"github.com/grafana/dskit/flagext"
+	"github.com/pkg/errors"
+
+	"github.com/grafana/loki/v3/pkg/storage/chunk/client"
+	"github.com/grafana/loki/v3/pkg/storage/chunk/client/hedging"
+)
+
+// errNotFound is the code some S3-compatible implementations use in place of
+// the official s3.ErrCodeNoSuchKey.
+const errNotFound = "NotFound"
+
+const (
+	// defaultMultipartUploadThreshold is the body size at or above which
+	// PutObject switches from a single PutObject call to a multipart upload.
+	defaultMultipartUploadThreshold = 128 * 1024 * 1024
+	// defaultMultipartPartSize is the size of each part uploaded once a
+	// multipart upload has been started.
+	defaultMultipartPartSize = 64 * 1024 * 1024
+	// defaultMultipartConcurrency is the number of parts uploaded in parallel.
+	defaultMultipartConcurrency = 4
+)
+
+// InjectRequestMiddleware gives users of this client the ability to inject
+// arbitrary middleware in front of the S3 request chain. The most common use
+// case for this is for tracing, though could be used for any reason.
+type InjectRequestMiddleware func(next http.RoundTripper) http.RoundTripper
+
+// Supported values of S3Config.SignatureVersion.
+const (
+	SignatureVersionV4     = "v4"
+	SignatureVersionV2     = "v2"
+	SignatureVersionNone   = "none"
+	SignatureVersionCustom = "custom"
+)
+
+// Supported values of S3Config.SDKVersion.
+const (
+	SDKVersionV1 = "v1"
+	SDKVersionV2 = "v2"
+)
+
+// S3Config specifies config for storing chunks and index on AWS S3.
+type S3Config struct {
+	S3               flagext.URLValue
+	BucketNames      string         `yaml:"bucketnames"`
+	Endpoint         string         `yaml:"endpoint"`
+	Region           string         `yaml:"region"`
+	AccessKeyID      string         `yaml:"access_key_id"`
+	SecretAccessKey  flagext.Secret `yaml:"secret_access_key"`
+	SessionToken     flagext.Secret `yaml:"session_token"`
+	Insecure         bool           `yaml:"insecure"`
+	S3ForcePathStyle bool           `yaml:"s3forcepathstyle"`
+
+	BackoffConfig backoff.Config `yaml:"backoff_config"`
+
+	// MultipartUploadThreshold is the body size at or above which PutObject
+	// uses a multipart upload instead of a single PutObject call. A multipart
+	// upload is also used whenever the body size cannot be determined up front
+	// (an io.Reader with neither a Len() method nor io.Seeker support).
+	MultipartUploadThreshold int64 `yaml:"multipart_upload_threshold"`
+	// MultipartPartSize is the size of each part of a multipart upload.
+	MultipartPartSize int64 `yaml:"multipart_part_size"`
+	// MultipartConcurrency is the number of parts uploaded concurrently
+	// during a multipart upload.
+	MultipartConcurrency int `yaml:"multipart_concurrency"`
+
+	// SignatureVersion is one of "v4" (the default), "v2", "none", or
+	// "custom". "v2" and "none" are useful against S3-compatible stores
+	// (older Ceph RGW, minio in legacy mode, some Wasabi regions) that don't
+	// support the SDK's default SigV4 signer. "custom" installs CustomSigner
+	// as the sole signing handler.
+	SignatureVersion string `yaml:"signature_version"`
+
+	// DefaultObjectTags are merged into the Tagging of every PutObject (and
+	// PutObjectWithTags) call, letting operators tag chunks/index objects
+	// with e.g. tenant/table for cost allocation and S3 Lifecycle rules.
+	// Tags passed explicitly to PutObjectWithTags take precedence over a
+	// same-named default tag.
+	DefaultObjectTags map[string]string `yaml:"default_object_tags"`
+
+	// Inject request middleware into the S3 client. Not exposed via YAML, only
+	// usable when setting config from code, e.g. for tracing.
+	Inject InjectRequestMiddleware `yaml:"-"`
+
+	// CustomSigner is installed as the request signer when SignatureVersion
+	// is "custom". Not exposed via YAML, only usable when setting config
+	// from code.
+	CustomSigner func(*request.Request) `yaml:"-"`
+
+	// SDKVersion selects which AWS SDK generation builds the S3 client: "v1"
+	// (the default, github.com/aws/aws-sdk-go) or "v2"
+	// (github.com/aws/aws-sdk-go-v2). "v2" is handled by
+	// pkg/storage/chunk/client/aws/v2, which only supports the base
+	// GetObject/PutObject/List/DeleteObject path; features added on top of
+	// that (multipart uploads, tagging, alternate signature versions) still
+	// require "v1".
+	SDKVersion string `yaml:"sdk_version"`
+}
+
+// RegisterFlags adds the flags required to config this to the given FlagSet.
+func (cfg *S3Config) RegisterFlags(f *flag.FlagSet) {
+	cfg.RegisterFlagsWithPrefix("s3.", f)
+}
+
+// RegisterFlagsWithPrefix adds the flags required to config this to the given FlagSet, with a specified prefix.
+func (cfg *S3Config) RegisterFlagsWithPrefix(prefix string, f *flag.FlagSet) {
+	f.Var(&cfg.S3, prefix+"url", "S3 endpoint URL with escaped Key and Secret encoded. If only region is specified as a host, proper endpoint will be deduced. Use inmemory:///<bucket-name> to use a mock in-memory implementation.")
+	f.StringVar(&cfg.BucketNames, prefix+"bucketnames", "", "Comma separated list of bucket names to evenly distribute chunks over. Overrides any buckets specified in s3.url flag.")
+	f.StringVar(&cfg.Endpoint, prefix+"endpoint", "", "S3 Endpoint to connect to.")
+	f.StringVar(&cfg.Region, prefix+"region", "", "AWS region to use.")
+	f.StringVar(&cfg.AccessKeyID, prefix+"access-key-id", "", "AWS Access Key ID")
+	f.Var(&cfg.SecretAccessKey, prefix+"secret-access-key", "AWS Secret Access Key")
+	f.Var(&cfg.SessionToken, prefix+"session-token", "AWS Session Token")
+	f.BoolVar(&cfg.Insecure, prefix+"insecure", false, "Disable https on S3 connection.")
+	f.BoolVar(&cfg.S3ForcePathStyle, prefix+"force-path-style", false, "Set this to `true` to force the request to use path-style addressing.")
+	f.Int64Var(&cfg.MultipartUploadThreshold, prefix+"multipart-upload-threshold", defaultMultipartUploadThreshold, "Body size at or above which PutObject switches to a multipart upload.")
+	f.Int64Var(&cfg.MultipartPartSize, prefix+"multipart-part-size", defaultMultipartPartSize, "Size of each part used in a multipart upload.")
+	f.IntVar(&cfg.MultipartConcurrency, prefix+"multipart-concurrency", defaultMultipartConcurrency, "Number of parts to upload concurrently for a multipart upload.")
+	f.StringVar(&cfg.SignatureVersion, prefix+"signature-version", SignatureVersionV4, fmt.Sprintf("The signature version to use for authenticating against S3. Supported values are: %s.", strings.Join([]string{SignatureVersionV4, SignatureVersionV2, SignatureVersionNone, SignatureVersionCustom}, ", ")))
+	f.StringVar(&cfg.SDKVersion, prefix+"sdk-version", SDKVersionV1, fmt.Sprintf("The AWS SDK generation to use for the S3 client. Supported values are: %s.", strings.Join([]string{SDKVersionV1, SDKVersionV2}, ", ")))
+	cfg.BackoffConfig.RegisterFlagsWithPrefix(prefix, f)
+}
+
+// S3ObjectClient is used to store chunks in AWS S3.
+type S3ObjectClient struct {
+	cfg S3Config
+
+	bucketNames []string
+	S3          s3iface.S3API
+	hedgedS3    s3iface.S3API
+}
+
+// NewS3ObjectClient makes a new S3-backed ObjectClient.
+func NewS3ObjectClient(cfg S3Config, hedgingCfg hedging.Config) (*S3ObjectClient, error) {
+	bucketNames := bucketsFromConfig(cfg)
+	if len(bucketNames) == 0 {
+		return nil, fmt.Errorf("at least one bucket name must be specified")
+	}
+
+	s3Client, err := buildS3Client(cfg, hedgingCfg, false)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to build S3 client")
+	}
+
+	hedgedS3Client, err := buildS3Client(cfg, hedgingCfg, true)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to build hedged S3 client")
+	}
+
+	return &S3ObjectClient{
+		cfg:         cfg,
+		S3:          s3Client,
+		hedgedS3:    hedgedS3Client,
+		bucketNames: bucketNames,
+	}, nil
+}
+
+func bucketsFromConfig(cfg S3Config) []string {
+	if cfg.BucketNames != "" {
+		return strings.Split(cfg.BucketNames, ",")
+	}
+	if cfg.S3.URL != nil && cfg.S3.URL.Path != "" {
+		return []string{strings.TrimPrefix(cfg.S3.URL.Path, "/")}
+	}
+	return nil
+}
+
+func buildS3Client(cfg S3Config, hedgingCfg hedging.Config, hedged bool) (s3iface.S3API, error) {
+	// The SDK's built-in retryer is disabled. Only PutObject (including each
+	// multipart part) retries, according to our own BackoffConfig; GetObject
+	// may be hedged; other calls are not retried.
+	s3Config := awsCommon.NewConfig().WithRegion(cfg.Region).WithMaxRetries(0)
+
+	if cfg.Endpoint != "" {
+		s3Config = s3Config.
+			WithEndpoint(cfg.Endpoint).
+			WithDisableSSL(cfg.Insecure).
+			WithS3ForcePathStyle(cfg.S3ForcePathStyle)
+	}
+
+	if cfg.AccessKeyID != "" || cfg.SecretAccessKey.String() != "" {
+		if cfg.AccessKeyID == "" || cfg.SecretAccessKey.String() == "" {
+			return nil, errors.New("must supply both an Access Key ID and Secret Access Key or neither")
+		}
+		s3Config = s3Config.WithCredentials(credentials.NewStaticCredentials(cfg.AccessKeyID, cfg.SecretAccessKey.String(), cfg.SessionToken.String()))
+	}
+
+	var transport http.RoundTripper = &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		MaxIdleConns:        200,
+		MaxIdleConnsPerHost: 200,
+		IdleConnTimeout:     90 * time.Second,
+	}
+
+	if hedged && hedgingCfg.At != 0 {
+		var err error
+		transport, err = hedgingCfg.RoundTripperWithCount(transport)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if cfg.Inject != nil {
+		transport = cfg.Inject(transport)
+	}
+
+	s3Config = s3Config.WithHTTPClient(&http.Client{Transport: transport})
+
+	sess, err := session.NewSession(s3Config)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create new AWS session")
+	}
+
+	svc := s3.New(sess)
+	if err := applySignatureVersion(svc, cfg); err != nil {
+		return nil, err
+	}
+
+	return svc, nil
+}
+
+// applySignatureVersion installs the signing handler matching
+// cfg.SignatureVersion on svc's Sign handler chain. The SDK's default SigV4
+// signer is left untouched for "v4" (and the empty/unset value).
+func applySignatureVersion(svc *s3.S3, cfg S3Config) error {
+	switch cfg.SignatureVersion {
+	case "", SignatureVersionV4:
+		// Nothing to do: the SDK installs its SigV4 signer by default.
+	case SignatureVersionV2:
+		svc.Handlers.Sign.Clear()
+		svc.Handlers.Sign.PushBack(signV2)
+	case SignatureVersionNone:
+		svc.Handlers.Sign.Clear()
+	case SignatureVersionCustom:
+		if cfg.CustomSigner == nil {
+			return errors.New("signature_version \"custom\" requires S3Config.CustomSigner to be set")
+		}
+		svc.Handlers.Sign.Clear()
+		svc.Handlers.Sign.PushBack(cfg.CustomSigner)
+	default:
+		return fmt.Errorf("unsupported signature_version %q", cfg.SignatureVersion)
+	}
+	return nil
+}
+
+// Stop fulfills the client.ObjectClient interface.
+func (a *S3ObjectClient) Stop() {}
+
+// GetObject returns a reader and the size for the specified object key from the configured S3 bucket.
+func (a *S3ObjectClient) GetObject(ctx context.Context, objectKey string) (io.ReadCloser, int64, error) {
+	resp, err := a.hedgedS3.GetObjectWithContext(ctx, &s3.GetObjectInput{
+		Bucket: awsCommon.String(a.bucket(objectKey)),
+		Key:    awsCommon.String(objectKey),
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	size := int64(-1)
+	if resp.ContentLength != nil {
+		size = *resp.ContentLength
+	}
+
+	return resp.Body, size, nil
+}
+
+// PutObject puts the specified bytes into the configured S3 bucket at the
+// provided key. Bodies at or above MultipartUploadThreshold, or whose size
+// can't be determined up front, are uploaded as a multipart upload so that
+// callers never need to buffer the whole body in memory.
+func (a *S3ObjectClient) PutObject(ctx context.Context, objectKey string, object io.Reader) error {
+	return a.putObjectWithTags(ctx, objectKey, object, nil)
+}
+
+// PutObjectWithTags behaves like PutObject, additionally tagging the object
+// with tags merged on top of S3Config.DefaultObjectTags (tags passed here
+// take precedence over same-named default tags). Tags are commonly used to
+// drive S3 Lifecycle rules and cost allocation, e.g. by tenant or table.
+func (a *S3ObjectClient) PutObjectWithTags(ctx context.Context, objectKey string, object io.Reader, tags map[string]string) error {
+	return a.putObjectWithTags(ctx, objectKey, object, tags)
+}
+
+func (a *S3ObjectClient) putObjectWithTags(ctx context.Context, objectKey string, object io.Reader, tags map[string]string) error {
+	tagging := encodeTags(mergeTags(a.cfg.DefaultObjectTags, tags))
+
+	threshold := a.cfg.MultipartUploadThreshold
+	if threshold <= 0 {
+		threshold = defaultMultipartUploadThreshold
+	}
+
+	if size, ok := readerLen(object); ok && size < threshold {
+		return a.putObject(ctx, objectKey, object, tagging)
+	}
+
+	return a.putObjectMultipart(ctx, objectKey, object, tagging)
+}
+
+// mergeTags merges override on top of base, with override taking precedence
+// on conflicting keys. Returns nil if both are empty.
+func mergeTags(base, override map[string]string) map[string]string {
+	if len(base) == 0 && len(override) == 0 {
+		return nil
+	}
+
+	merged := make(map[string]string, len(base)+len(override))
+	for k, v := range base {
+		merged[k] = v
+	}
+	for k, v := range override {
+		merged[k] = v
+	}
+	return merged
+}
+
+// encodeTags url-encodes tags into the form expected by PutObjectInput.Tagging
+// / CreateMultipartUploadInput.Tagging: "key1=value1&key2=value2".
+func encodeTags(tags map[string]string) string {
+	if len(tags) == 0 {
+		return ""
+	}
+
+	values := url.Values{}
+	for k, v := range tags {
+		values.Set(k, v)
+	}
+	return values.Encode()
+}
+
+// readerLen returns the number of bytes remaining to be read from r, and
+// whether that could be determined without consuming r.
+func readerLen(r io.Reader) (int64, bool) {
+	if lr, ok := r.(interface{ Len() int }); ok {
+		return int64(lr.Len()), true
+	}
+	if s, ok := r.(io.Seeker); ok {
+		if n, err := awsCommon.SeekerLen(s); err == nil {
+			return n, true
+		}
+	}
+	return 0, false
+}
+
+// putObject uploads the whole body in a single PutObject call, retrying
+// according to BackoffConfig. The body is buffered up front and re-wrapped
+// on every attempt: object isn't guaranteed to be seekable (e.g. a
+// *bytes.Buffer, which readerLen routes here via its Len() method but which
+// can't be rewound), so reusing a single Body across retries would resend
+// from wherever the previous attempt left off.
+func (a *S3ObjectClient) putObject(ctx context.Context, objectKey string, object io.Reader, tagging string) error {
+	body, err := io.ReadAll(object)
+	if err != nil {
+		return err
+	}
+
+	input := &s3.PutObjectInput{
+		Bucket: awsCommon.String(a.bucket(objectKey)),
+		Key:    awsCommon.String(objectKey),
+	}
+	if tagging != "" {
+		input.Tagging = awsCommon.String(tagging)
+	}
+
+	var lastErr error
+	boff := backoff.New(ctx, a.cfg.BackoffConfig)
+	for boff.Ongoing() {
+		input.Body = bytes.NewReader(body)
+		_, lastErr = a.S3.PutObjectWithContext(ctx, input)
+		if lastErr == nil {
+			return nil
+		}
+		boff.Wait()
+	}
+	if lastErr != nil {
+		return lastErr
+	}
+	return boff.Err()
+}
+
+// putObjectMultipart uploads object as a sequence of parts of
+// MultipartPartSize bytes, uploading up to MultipartConcurrency parts in
+// parallel. Each part is retried independently according to BackoffConfig.
+// If any part fails, or the context is cancelled, the multipart upload is
+// aborted. An empty object produces zero parts, which S3 rejects on
+// complete ("you must specify at least one part"), so that case aborts the
+// multipart upload and falls back to a plain zero-byte putObject instead.
+func (a *S3ObjectClient) putObjectMultipart(ctx context.Context, objectKey string, object io.Reader, tagging string) error {
+	bucket := a.bucket(objectKey)
+
+	createInput := &s3.CreateMultipartUploadInput{
+		Bucket: awsCommon.String(bucket),
+		Key:    awsCommon.String(objectKey),
+	}
+	if tagging != "" {
+		createInput.Tagging = awsCommon.String(tagging)
+	}
+
+	created, err := a.S3.CreateMultipartUploadWithContext(ctx, createInput)
+	if err != nil {
+		return errors.Wrap(err, "failed to create multipart upload")
+	}
+	uploadID := created.UploadId
+
+	partSize := a.cfg.MultipartPartSize
+	if partSize <= 0 {
+		partSize = defaultMultipartPartSize
+	}
+	concurrency := a.cfg.MultipartConcurrency
+	if concurrency <= 0 {
+		concurrency = defaultMultipartConcurrency
+	}
+
+	var (
+		wg       sync.WaitGroup
+		sem      = make(chan struct{}, concurrency)
+		mtx      sync.Mutex
+		parts    []*s3.CompletedPart
+		firstErr error
+	)
+
+	buf := make([]byte, partSize)
+	partNumber := int64(1)
+
+readLoop:
+	for {
+		n, readErr := io.ReadFull(object, buf)
+		if n == 0 {
+			if readErr == io.EOF {
+				break
+			}
+			if readErr != nil {
+				mtx.Lock()
+				if firstErr == nil {
+					firstErr = readErr
+				}
+				mtx.Unlock()
+				break
+			}
+		}
+
+		data := make([]byte, n)
+		copy(data, buf[:n])
+		pn := partNumber
+		partNumber++
+
+		select {
+		case <-ctx.Done():
+			mtx.Lock()
+			if firstErr == nil {
+				firstErr = ctx.Err()
+			}
+			mtx.Unlock()
+			break readLoop
+		case sem <- struct{}{}:
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			etag, uploadErr := a.uploadPart(ctx, bucket, objectKey, uploadID, pn, data)
+
+			mtx.Lock()
+			defer mtx.Unlock()
+			if uploadErr != nil {
+				if firstErr == nil {
+					firstErr = uploadErr
+				}
+				return
+			}
+			parts = append(parts, &s3.CompletedPart{ETag: etag, PartNumber: awsCommon.Int64(pn)})
+		}()
+
+		if readErr == io.EOF || readErr == io.ErrUnexpectedEOF {
+			break
+		}
+		if readErr != nil {
+			mtx.Lock()
+			if firstErr == nil {
+				firstErr = readErr
+			}
+			mtx.Unlock()
+			break
+		}
+	}
+
+	wg.Wait()
+
+	if firstErr != nil {
+		a.abortMultipartUpload(bucket, objectKey, uploadID)
+		return firstErr
+	}
+
+	if len(parts) == 0 {
+		a.abortMultipartUpload(bucket, objectKey, uploadID)
+		return a.putObject(ctx, objectKey, bytes.NewReader(nil), tagging)
+	}
+
+	sort.Slice(parts, func(i, j int) bool { return *parts[i].PartNumber < *parts[j].PartNumber })
+
+	if _, err := a.S3.CompleteMultipartUploadWithContext(ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:          awsCommon.String(bucket),
+		Key:             awsCommon.String(objectKey),
+		UploadId:        uploadID,
+		MultipartUpload: &s3.CompletedMultipartUpload{Parts: parts},
+	}); err != nil {
+		a.abortMultipartUpload(bucket, objectKey, uploadID)
+		return errors.Wrap(err, "failed to complete multipart upload")
+	}
+
+	return nil
+}
+
+// uploadPart uploads a single part, retrying according to BackoffConfig.
+func (a *S3ObjectClient) uploadPart(ctx context.Context, bucket, objectKey string, uploadID *string, partNumber int64, data []byte) (*string, error) {
+	var lastErr error
+	boff := backoff.New(ctx, a.cfg.BackoffConfig)
+	for boff.Ongoing() {
+		var resp *s3.UploadPartOutput
+		resp, lastErr = a.S3.UploadPartWithContext(ctx, &s3.UploadPartInput{
+			Bucket:     awsCommon.String(bucket),
+			Key:        awsCommon.String(objectKey),
+			UploadId:   uploadID,
+			PartNumber: awsCommon.Int64(partNumber),
+			Body:       bytes.NewReader(data),
+		})
+		if lastErr == nil {
+			return resp.ETag, nil
+		}
+		boff.Wait()
+	}
+	if lastErr != nil {
+		return nil, lastErr
+	}
+	return nil, boff.Err()
+}
+
+// abortMultipartUpload best-effort aborts an in-flight multipart upload so
+// that S3 doesn't keep billing for the orphaned parts. It uses a fresh
+// context since ctx may already be cancelled.
+func (a *S3ObjectClient) abortMultipartUpload(bucket, objectKey string, uploadID *string) {
+	_, _ = a.S3.AbortMultipartUploadWithContext(context.Background(), &s3.AbortMultipartUploadInput{
+		Bucket:   awsCommon.String(bucket),
+		Key:      awsCommon.String(objectKey),
+		UploadId: uploadID,
+	})
+}
+
+// List implements the client.ObjectClient interface.
+func (a *S3ObjectClient) List(ctx context.Context, prefix, delimiter string) ([]client.StorageObject, []client.StorageCommonPrefix, error) {
+	var storageObjects []client.StorageObject
+	var commonPrefixes []client.StorageCommonPrefix
+
+	for i := range a.bucketNames {
+		input := s3.ListObjectsV2Input{
+			Bucket:    awsCommon.String(a.bucketNames[i]),
+			Prefix:    awsCommon.String(prefix),
+			Delimiter: awsCommon.String(delimiter),
+		}
+
+		for {
+			output, err := a.S3.ListObjectsV2WithContext(ctx, &input)
+			if err != nil {
+				return nil, nil, err
+			}
+
+			for _, content := range output.Contents {
+				storageObjects = append(storageObjects, client.StorageObject{
+					Key:        *content.Key,
+					ModifiedAt: *content.LastModified,
+				})
+			}
+
+			for _, commonPrefix := range output.CommonPrefixes {
+				commonPrefixes = append(commonPrefixes, client.StorageCommonPrefix(*commonPrefix.Prefix))
+			}
+
+			if output.IsTruncated == nil || !*output.IsTruncated {
+				break
+			}
+			input.ContinuationToken = output.NextContinuationToken
+		}
+	}
+
+	return storageObjects, commonPrefixes, nil
+}
+
+// DeleteObject deletes the specified object key from the configured S3 bucket.
+func (a *S3ObjectClient) DeleteObject(ctx context.Context, objectKey string) error {
+	_, err := a.S3.DeleteObjectWithContext(ctx, &s3.DeleteObjectInput{
+		Bucket: awsCommon.String(a.bucket(objectKey)),
+		Key:    awsCommon.String(objectKey),
+	})
+	return err
+}
+
+// GetObjectTags returns the set of tags currently attached to objectKey.
+func (a *S3ObjectClient) GetObjectTags(ctx context.Context, objectKey string) (map[string]string, error) {
+	resp, err := a.S3.GetObjectTaggingWithContext(ctx, &s3.GetObjectTaggingInput{
+		Bucket: awsCommon.String(a.bucket(objectKey)),
+		Key:    awsCommon.String(objectKey),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	tags := make(map[string]string, len(resp.TagSet))
+	for _, t := range resp.TagSet {
+		tags[awsCommon.StringValue(t.Key)] = awsCommon.StringValue(t.Value)
+	}
+	return tags, nil
+}
+
+// SetObjectTags replaces the full set of tags attached to objectKey with
+// tags, overwriting any tags previously set (including the defaults applied
+// at PutObject time via S3Config.DefaultObjectTags).
+func (a *S3ObjectClient) SetObjectTags(ctx context.Context, objectKey string, tags map[string]string) error {
+	_, err := a.S3.PutObjectTaggingWithContext(ctx, &s3.PutObjectTaggingInput{
+		Bucket:  awsCommon.String(a.bucket(objectKey)),
+		Key:     awsCommon.String(objectKey),
+		Tagging: &s3.Tagging{TagSet: tagSet(tags)},
+	})
+	return err
+}
+
+// tagSet converts tags into the []*s3.Tag representation used by the
+// tagging APIs, in a deterministic key order.
+func tagSet(tags map[string]string) []*s3.Tag {
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	set := make([]*s3.Tag, 0, len(keys))
+	for _, k := range keys {
+		set = append(set, &s3.Tag{Key: awsCommon.String(k), Value: awsCommon.String(tags[k])})
+	}
+	return set
+}
+
+// bucket deterministically picks one of the configured buckets for the given
+// object key, so that chunks and index entries are evenly spread across all
+// of them.
+func (a *S3ObjectClient) bucket(objectKey string) string {
+	if len(a.bucketNames) == 1 {
+		return a.bucketNames[0]
+	}
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(objectKey))
+	return a.bucketNames[h.Sum32()%uint32(len(a.bucketNames))]
+}
+
+// IsObjectNotFoundErr returns true if error means that object is not found. Returns false if err is nil.
+func (a *S3ObjectClient) IsObjectNotFoundErr(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	if aerr, ok := err.(awserr.Error); ok {
+		switch aerr.Code() {
+		case s3.ErrCodeNoSuchKey, errNotFound:
+			return true
+		}
+	}
+
+	return false
+}
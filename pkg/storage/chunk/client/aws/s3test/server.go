@@ -0,0 +1,570 @@
+// Package s3test provides an in-process fake S3 server for exercising
+// S3ObjectClient against real HTTP requests (signing, pagination, multipart,
+// error responses) instead of mocking out the s3iface.S3API interface.
+//
+// It only implements the subset of the S3 REST API that S3ObjectClient
+// relies on: GET/PUT/DELETE/HEAD object, ListObjectsV2, the multipart
+// upload sequence (CreateMultipartUpload/UploadPart/CompleteMultipartUpload/
+// AbortMultipartUpload), and object tagging (GetObjectTagging/
+// PutObjectTagging, plus the Tagging header on PutObject/
+// CreateMultipartUpload).
+package s3test
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrorCode is one of the canned S3 error codes the server can be told to
+// return instead of serving a request.
+type ErrorCode string
+
+const (
+	ErrNoSuchKey    ErrorCode = "NoSuchKey"
+	ErrNoSuchBucket ErrorCode = "NoSuchBucket"
+	ErrSlowDown     ErrorCode = "SlowDown"
+	ErrInternal     ErrorCode = "InternalError"
+)
+
+var statusForCode = map[ErrorCode]int{
+	ErrNoSuchKey:    http.StatusNotFound,
+	ErrNoSuchBucket: http.StatusNotFound,
+	ErrSlowDown:     http.StatusServiceUnavailable,
+	ErrInternal:     http.StatusInternalServerError,
+}
+
+type object struct {
+	body         []byte
+	etag         string
+	lastModified time.Time
+	tags         map[string]string
+}
+
+type multipartUpload struct {
+	bucket, key string
+	parts       map[int64][]byte
+	tags        map[string]string
+}
+
+// Server is an in-process fake S3 server backed by an httptest.Server.
+// Objects are held in memory, keyed by bucket and key.
+type Server struct {
+	*httptest.Server
+
+	mu      sync.Mutex
+	buckets map[string]map[string]*object
+	uploads map[string]*multipartUpload
+
+	latency     time.Duration
+	injected    map[string]ErrorCode // verb -> forced error code
+	injectedN   map[string]int       // verb -> remaining number of times to inject
+	requests    map[string]int32     // verb -> number of requests seen
+	lastHeaders http.Header          // headers of the most recently received request
+	nextUpload  int64
+}
+
+// NewServer starts a fake S3 server with the given buckets pre-created.
+func NewServer(buckets ...string) *Server {
+	s := &Server{
+		buckets:   map[string]map[string]*object{},
+		uploads:   map[string]*multipartUpload{},
+		injected:  map[string]ErrorCode{},
+		injectedN: map[string]int{},
+		requests:  map[string]int32{},
+	}
+	for _, b := range buckets {
+		s.buckets[b] = map[string]*object{}
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+// SetLatency makes every request sleep for d before being handled, so tests
+// can exercise hedging/backoff behavior.
+func (s *Server) SetLatency(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.latency = d
+}
+
+// InjectError forces the next n requests using the given HTTP verb (GET, PUT,
+// DELETE, HEAD, POST) to fail with code instead of being served normally. A
+// negative n injects the error for every future request.
+func (s *Server) InjectError(verb string, code ErrorCode, n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.injected[verb] = code
+	s.injectedN[verb] = n
+}
+
+// RequestCount returns how many requests using the given HTTP verb the
+// server has received so far.
+func (s *Server) RequestCount(verb string) int32 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests[verb]
+}
+
+// TotalRequests returns how many requests the server has received so far,
+// across all HTTP verbs.
+func (s *Server) TotalRequests() int32 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var total int32
+	for _, n := range s.requests {
+		total += n
+	}
+	return total
+}
+
+// LastRequestHeaders returns the headers of the most recently received
+// request, so tests can assert on signing behavior (e.g. the Authorization
+// header).
+func (s *Server) LastRequestHeaders() http.Header {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lastHeaders
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.requests[r.Method]++
+	s.lastHeaders = r.Header.Clone()
+	latency := s.latency
+	var forced ErrorCode
+	if code, ok := s.injected[r.Method]; ok && s.injectedN[r.Method] != 0 {
+		forced = code
+		if s.injectedN[r.Method] > 0 {
+			s.injectedN[r.Method]--
+		}
+	}
+	s.mu.Unlock()
+
+	if latency > 0 {
+		time.Sleep(latency)
+	}
+
+	if forced != "" {
+		writeError(w, forced)
+		return
+	}
+
+	bucket, key := splitPath(r.URL.Path)
+
+	s.mu.Lock()
+	objects, ok := s.buckets[bucket]
+	s.mu.Unlock()
+	if !ok {
+		writeError(w, ErrNoSuchBucket)
+		return
+	}
+
+	switch {
+	case r.Method == http.MethodGet && r.URL.Query().Get("list-type") == "2":
+		s.listObjectsV2(w, r, bucket, objects)
+	case r.Method == http.MethodPost && hasQueryKey(r, "uploads"):
+		s.createMultipartUpload(w, r, bucket, key)
+	case r.Method == http.MethodPut && r.URL.Query().Get("uploadId") != "" && r.URL.Query().Get("partNumber") != "":
+		s.uploadPart(w, r, bucket, key)
+	case r.Method == http.MethodPost && r.URL.Query().Get("uploadId") != "":
+		s.completeMultipartUpload(w, r, bucket, key)
+	case r.Method == http.MethodDelete && r.URL.Query().Get("uploadId") != "":
+		s.abortMultipartUpload(w, r, bucket, key)
+	case r.Method == http.MethodGet && hasQueryKey(r, "tagging"):
+		s.getObjectTagging(w, bucket, key, objects)
+	case r.Method == http.MethodPut && hasQueryKey(r, "tagging"):
+		s.putObjectTagging(w, r, bucket, key, objects)
+	case r.Method == http.MethodPut:
+		s.putObject(w, r, bucket, key, objects)
+	case r.Method == http.MethodGet:
+		s.getObject(w, bucket, key, objects)
+	case r.Method == http.MethodHead:
+		s.headObject(w, bucket, key, objects)
+	case r.Method == http.MethodDelete:
+		s.deleteObject(w, bucket, key, objects)
+	default:
+		http.Error(w, "unsupported method", http.StatusMethodNotAllowed)
+	}
+}
+
+func splitPath(path string) (bucket, key string) {
+	path = strings.TrimPrefix(path, "/")
+	parts := strings.SplitN(path, "/", 2)
+	if len(parts) == 1 {
+		return parts[0], ""
+	}
+	return parts[0], parts[1]
+}
+
+func hasQueryKey(r *http.Request, key string) bool {
+	_, ok := r.URL.Query()[key]
+	return ok
+}
+
+func writeError(w http.ResponseWriter, code ErrorCode) {
+	status, ok := statusForCode[code]
+	if !ok {
+		status = http.StatusBadRequest
+	}
+	w.WriteHeader(status)
+	_ = xml.NewEncoder(w).Encode(struct {
+		XMLName xml.Name `xml:"Error"`
+		Code    string
+		Message string
+	}{Code: string(code), Message: string(code)})
+}
+
+func (s *Server) putObject(w http.ResponseWriter, r *http.Request, bucket, key string, objects map[string]*object) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, ErrInternal)
+		return
+	}
+
+	obj := &object{body: body, etag: etagFor(body), lastModified: time.Now().UTC(), tags: parseTagging(r.Header.Get("x-amz-tagging"))}
+
+	s.mu.Lock()
+	objects[key] = obj
+	s.mu.Unlock()
+
+	w.Header().Set("ETag", obj.etag)
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) getObject(w http.ResponseWriter, bucket, key string, objects map[string]*object) {
+	s.mu.Lock()
+	obj, ok := objects[key]
+	s.mu.Unlock()
+	if !ok {
+		writeError(w, ErrNoSuchKey)
+		return
+	}
+
+	w.Header().Set("ETag", obj.etag)
+	w.Header().Set("Last-Modified", obj.lastModified.Format(http.TimeFormat))
+	w.Header().Set("Content-Length", strconv.Itoa(len(obj.body)))
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(obj.body)
+}
+
+func (s *Server) headObject(w http.ResponseWriter, bucket, key string, objects map[string]*object) {
+	s.mu.Lock()
+	obj, ok := objects[key]
+	s.mu.Unlock()
+	if !ok {
+		writeError(w, ErrNoSuchKey)
+		return
+	}
+
+	w.Header().Set("ETag", obj.etag)
+	w.Header().Set("Last-Modified", obj.lastModified.Format(http.TimeFormat))
+	w.Header().Set("Content-Length", strconv.Itoa(len(obj.body)))
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) deleteObject(w http.ResponseWriter, bucket, key string, objects map[string]*object) {
+	s.mu.Lock()
+	delete(objects, key)
+	s.mu.Unlock()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type listBucketResult struct {
+	XMLName               xml.Name `xml:"ListBucketResult"`
+	Name                  string
+	Prefix                string
+	Delimiter             string `xml:",omitempty"`
+	IsTruncated           bool
+	NextContinuationToken string `xml:",omitempty"`
+	Contents              []struct {
+		Key          string
+		LastModified string
+		ETag         string
+	}
+	CommonPrefixes []struct {
+		Prefix string
+	}
+}
+
+// listPageSize bounds how many keys a single ListObjectsV2 response returns,
+// so tests can exercise continuation-token pagination.
+const listPageSize = 1000
+
+func (s *Server) listObjectsV2(w http.ResponseWriter, r *http.Request, bucket string, objects map[string]*object) {
+	prefix := r.URL.Query().Get("prefix")
+	delimiter := r.URL.Query().Get("delimiter")
+	continuationToken := r.URL.Query().Get("continuation-token")
+
+	s.mu.Lock()
+	keys := make([]string, 0, len(objects))
+	for k := range objects {
+		if strings.HasPrefix(k, prefix) {
+			keys = append(keys, k)
+		}
+	}
+	s.mu.Unlock()
+	sort.Strings(keys)
+
+	start := 0
+	if continuationToken != "" {
+		for i, k := range keys {
+			if k > continuationToken {
+				start = i
+				break
+			}
+		}
+	}
+
+	result := listBucketResult{Name: bucket, Prefix: prefix, Delimiter: delimiter}
+	seenPrefixes := map[string]bool{}
+
+	end := start
+	for ; end < len(keys) && end-start < listPageSize; end++ {
+		key := keys[end]
+		rest := strings.TrimPrefix(key, prefix)
+
+		if delimiter != "" {
+			if idx := strings.Index(rest, delimiter); idx >= 0 {
+				commonPrefix := prefix + rest[:idx+len(delimiter)]
+				if !seenPrefixes[commonPrefix] {
+					seenPrefixes[commonPrefix] = true
+					result.CommonPrefixes = append(result.CommonPrefixes, struct{ Prefix string }{commonPrefix})
+				}
+				continue
+			}
+		}
+
+		s.mu.Lock()
+		obj := objects[key]
+		s.mu.Unlock()
+
+		result.Contents = append(result.Contents, struct {
+			Key          string
+			LastModified string
+			ETag         string
+		}{Key: key, LastModified: obj.lastModified.Format(time.RFC3339), ETag: obj.etag})
+	}
+
+	if end < len(keys) {
+		result.IsTruncated = true
+		result.NextContinuationToken = keys[end-1]
+	}
+
+	w.Header().Set("Content-Type", "application/xml")
+	_ = xml.NewEncoder(w).Encode(result)
+}
+
+func (s *Server) createMultipartUpload(w http.ResponseWriter, r *http.Request, bucket, key string) {
+	tags := parseTagging(r.Header.Get("x-amz-tagging"))
+
+	s.mu.Lock()
+	s.nextUpload++
+	uploadID := fmt.Sprintf("upload-%d", s.nextUpload)
+	s.uploads[uploadID] = &multipartUpload{bucket: bucket, key: key, parts: map[int64][]byte{}, tags: tags}
+	s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/xml")
+	_ = xml.NewEncoder(w).Encode(struct {
+		XMLName  xml.Name `xml:"InitiateMultipartUploadResult"`
+		Bucket   string
+		Key      string
+		UploadId string
+	}{Bucket: bucket, Key: key, UploadId: uploadID})
+}
+
+func (s *Server) uploadPart(w http.ResponseWriter, r *http.Request, bucket, key string) {
+	uploadID := r.URL.Query().Get("uploadId")
+	partNumber, err := strconv.ParseInt(r.URL.Query().Get("partNumber"), 10, 64)
+	if err != nil {
+		writeError(w, ErrInternal)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, ErrInternal)
+		return
+	}
+
+	s.mu.Lock()
+	upload, ok := s.uploads[uploadID]
+	if ok {
+		upload.parts[partNumber] = body
+	}
+	s.mu.Unlock()
+	if !ok {
+		writeError(w, ErrNoSuchKey)
+		return
+	}
+
+	w.Header().Set("ETag", etagFor(body))
+	w.WriteHeader(http.StatusOK)
+}
+
+type completeMultipartUploadRequest struct {
+	Part []struct {
+		PartNumber int64
+		ETag       string
+	}
+}
+
+func (s *Server) completeMultipartUpload(w http.ResponseWriter, r *http.Request, bucket, key string) {
+	uploadID := r.URL.Query().Get("uploadId")
+
+	var req completeMultipartUploadRequest
+	if err := xml.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, ErrInternal)
+		return
+	}
+
+	s.mu.Lock()
+	upload, ok := s.uploads[uploadID]
+	if !ok {
+		s.mu.Unlock()
+		writeError(w, ErrNoSuchKey)
+		return
+	}
+
+	parts := make([]int64, 0, len(req.Part))
+	for _, p := range req.Part {
+		parts = append(parts, p.PartNumber)
+	}
+	sort.Slice(parts, func(i, j int) bool { return parts[i] < parts[j] })
+
+	var body []byte
+	for _, pn := range parts {
+		body = append(body, upload.parts[pn]...)
+	}
+
+	objects := s.buckets[bucket]
+	obj := &object{body: body, etag: etagFor(body), lastModified: time.Now().UTC(), tags: upload.tags}
+	objects[key] = obj
+	delete(s.uploads, uploadID)
+	s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/xml")
+	_ = xml.NewEncoder(w).Encode(struct {
+		XMLName xml.Name `xml:"CompleteMultipartUploadResult"`
+		Bucket  string
+		Key     string
+		ETag    string
+	}{Bucket: bucket, Key: key, ETag: obj.etag})
+}
+
+func (s *Server) abortMultipartUpload(w http.ResponseWriter, r *http.Request, bucket, key string) {
+	uploadID := r.URL.Query().Get("uploadId")
+
+	s.mu.Lock()
+	delete(s.uploads, uploadID)
+	s.mu.Unlock()
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type tagging struct {
+	XMLName xml.Name `xml:"Tagging"`
+	TagSet  []struct {
+		Key   string
+		Value string
+	} `xml:"TagSet>Tag"`
+}
+
+// parseTagging decodes the "key1=value1&key2=value2" form sent in the
+// x-amz-tagging header of PutObject/CreateMultipartUpload requests.
+func parseTagging(raw string) map[string]string {
+	if raw == "" {
+		return nil
+	}
+	values, err := url.ParseQuery(raw)
+	if err != nil {
+		return nil
+	}
+	tags := make(map[string]string, len(values))
+	for k := range values {
+		tags[k] = values.Get(k)
+	}
+	return tags
+}
+
+func (s *Server) getObjectTagging(w http.ResponseWriter, bucket, key string, objects map[string]*object) {
+	s.mu.Lock()
+	obj, ok := objects[key]
+	s.mu.Unlock()
+	if !ok {
+		writeError(w, ErrNoSuchKey)
+		return
+	}
+
+	result := tagging{}
+	keys := make([]string, 0, len(obj.tags))
+	for k := range obj.tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		result.TagSet = append(result.TagSet, struct {
+			Key   string
+			Value string
+		}{Key: k, Value: obj.tags[k]})
+	}
+
+	w.Header().Set("Content-Type", "application/xml")
+	_ = xml.NewEncoder(w).Encode(result)
+}
+
+func (s *Server) putObjectTagging(w http.ResponseWriter, r *http.Request, bucket, key string, objects map[string]*object) {
+	s.mu.Lock()
+	obj, ok := objects[key]
+	s.mu.Unlock()
+	if !ok {
+		writeError(w, ErrNoSuchKey)
+		return
+	}
+
+	var req tagging
+	if err := xml.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, ErrInternal)
+		return
+	}
+
+	tags := make(map[string]string, len(req.TagSet))
+	for _, t := range req.TagSet {
+		tags[t.Key] = t.Value
+	}
+
+	s.mu.Lock()
+	obj.tags = tags
+	s.mu.Unlock()
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func etagFor(body []byte) string {
+	h := fnv32a(body)
+	return fmt.Sprintf(`"%08x"`, h)
+}
+
+// fnv32a is a tiny dependency-free stand-in for a real content hash; the
+// fake server only needs ETags to be stable and to change with content, not
+// to match AWS's actual MD5-based ETags.
+func fnv32a(data []byte) uint32 {
+	const (
+		offset32 = 2166136261
+		prime32  = 16777619
+	)
+	hash := uint32(offset32)
+	for _, b := range data {
+		hash ^= uint32(b)
+		hash *= prime32
+	}
+	return hash
+}
@@ -0,0 +1,285 @@
+// Package v2 is an aws-sdk-go-v2-based implementation of the S3 object
+// client. It covers the same base operations as the v1 client in the parent
+// aws package: GetObject, PutObject, List, DeleteObject, and
+// IsObjectNotFoundErr. Features layered on top of those in the v1 client
+// (multipart uploads, tagging, alternate signature versions) aren't part of
+// this shim yet, so S3Config.SDKVersion still needs to be "v1" to use them.
+//
+// New dispatches to either implementation based on S3Config.SDKVersion, so
+// most callers should use New rather than constructing S3ObjectClient here
+// directly.
+package v2
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	awssdk "github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/grafana/dskit/backoff"
+
+	"github.com/grafana/loki/v3/pkg/storage/chunk/client"
+	awsv1 "github.com/grafana/loki/v3/pkg/storage/chunk/client/aws"
+	"github.com/grafana/loki/v3/pkg/storage/chunk/client/hedging"
+)
+
+// New builds an S3 ObjectClient, using the aws-sdk-go-v2-based
+// implementation in this package if cfg.SDKVersion is awsv1.SDKVersionV2, or
+// falling back to the v1 implementation in the parent aws package otherwise.
+func New(cfg awsv1.S3Config, hedgingCfg hedging.Config) (client.ObjectClient, error) {
+	if cfg.SDKVersion != awsv1.SDKVersionV2 {
+		return awsv1.NewS3ObjectClient(cfg, hedgingCfg)
+	}
+	return NewS3ObjectClient(cfg, hedgingCfg)
+}
+
+// S3ObjectClient is used to store chunks in AWS S3, via aws-sdk-go-v2.
+type S3ObjectClient struct {
+	cfg awsv1.S3Config
+
+	bucketNames []string
+	s3          *s3.Client
+	hedgedS3    *s3.Client
+}
+
+// NewS3ObjectClient makes a new S3-backed ObjectClient using aws-sdk-go-v2.
+func NewS3ObjectClient(cfg awsv1.S3Config, hedgingCfg hedging.Config) (*S3ObjectClient, error) {
+	bucketNames := bucketsFromConfig(cfg)
+	if len(bucketNames) == 0 {
+		return nil, fmt.Errorf("at least one bucket name must be specified")
+	}
+
+	s3Client, err := buildS3Client(cfg, hedgingCfg, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build S3 client: %w", err)
+	}
+
+	hedgedS3Client, err := buildS3Client(cfg, hedgingCfg, true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build hedged S3 client: %w", err)
+	}
+
+	return &S3ObjectClient{
+		cfg:         cfg,
+		s3:          s3Client,
+		hedgedS3:    hedgedS3Client,
+		bucketNames: bucketNames,
+	}, nil
+}
+
+// regionOrDefault falls back to a placeholder region when none is
+// configured, e.g. when pointing at an S3-compatible endpoint that doesn't
+// use AWS regions. SigV4 still requires some region to be present.
+func regionOrDefault(region string) string {
+	if region != "" {
+		return region
+	}
+	return "us-east-1"
+}
+
+func bucketsFromConfig(cfg awsv1.S3Config) []string {
+	if cfg.BucketNames != "" {
+		return strings.Split(cfg.BucketNames, ",")
+	}
+	if cfg.S3.URL != nil && cfg.S3.URL.Path != "" {
+		return []string{strings.TrimPrefix(cfg.S3.URL.Path, "/")}
+	}
+	return nil
+}
+
+func buildS3Client(cfg awsv1.S3Config, hedgingCfg hedging.Config, hedged bool) (*s3.Client, error) {
+	var transport http.RoundTripper = &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		MaxIdleConns:        200,
+		MaxIdleConnsPerHost: 200,
+		IdleConnTimeout:     90 * time.Second,
+	}
+
+	if hedged && hedgingCfg.At != 0 {
+		var err error
+		transport, err = hedgingCfg.RoundTripperWithCount(transport)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if cfg.Inject != nil {
+		transport = cfg.Inject(transport)
+	}
+
+	opts := []func(*awsconfig.LoadOptions) error{
+		awsconfig.WithRegion(regionOrDefault(cfg.Region)),
+		awsconfig.WithHTTPClient(&http.Client{Transport: transport}),
+	}
+
+	if cfg.AccessKeyID != "" || cfg.SecretAccessKey.String() != "" {
+		if cfg.AccessKeyID == "" || cfg.SecretAccessKey.String() == "" {
+			return nil, errors.New("must supply both an Access Key ID and Secret Access Key or neither")
+		}
+		opts = append(opts, awsconfig.WithCredentialsProvider(
+			credentials.NewStaticCredentialsProvider(cfg.AccessKeyID, cfg.SecretAccessKey.String(), cfg.SessionToken.String()),
+		))
+	}
+
+	awsCfg, err := awsconfig.LoadDefaultConfig(context.Background(), opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS config: %w", err)
+	}
+
+	return s3.NewFromConfig(awsCfg, func(o *s3.Options) {
+		if cfg.Endpoint != "" {
+			o.BaseEndpoint = awssdk.String(cfg.Endpoint)
+		}
+		o.UsePathStyle = cfg.S3ForcePathStyle
+		// The SDK's built-in retryer is disabled. Only PutObject retries,
+		// according to our own BackoffConfig; GetObject may be hedged; other
+		// calls are not retried.
+		o.RetryMaxAttempts = 1
+	}), nil
+}
+
+// Stop fulfills the client.ObjectClient interface.
+func (a *S3ObjectClient) Stop() {}
+
+// GetObject returns a reader and the size for the specified object key from the configured S3 bucket.
+func (a *S3ObjectClient) GetObject(ctx context.Context, objectKey string) (io.ReadCloser, int64, error) {
+	resp, err := a.hedgedS3.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: awssdk.String(a.bucket(objectKey)),
+		Key:    awssdk.String(objectKey),
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	size := int64(-1)
+	if resp.ContentLength != nil {
+		size = *resp.ContentLength
+	}
+
+	return resp.Body, size, nil
+}
+
+// PutObject puts the specified bytes into the configured S3 bucket at the
+// provided key, retrying according to BackoffConfig. Unlike the v1 client,
+// this implementation always buffers the body and issues a single
+// PutObject call: the multipart upload path isn't part of the v2 shim yet.
+func (a *S3ObjectClient) PutObject(ctx context.Context, objectKey string, object io.Reader) error {
+	body, err := io.ReadAll(object)
+	if err != nil {
+		return err
+	}
+
+	input := &s3.PutObjectInput{
+		Bucket: awssdk.String(a.bucket(objectKey)),
+		Key:    awssdk.String(objectKey),
+	}
+
+	var lastErr error
+	boff := backoff.New(ctx, a.cfg.BackoffConfig)
+	for boff.Ongoing() {
+		input.Body = bytes.NewReader(body)
+		_, lastErr = a.s3.PutObject(ctx, input)
+		if lastErr == nil {
+			return nil
+		}
+		boff.Wait()
+	}
+	if lastErr != nil {
+		return lastErr
+	}
+	return boff.Err()
+}
+
+// List implements the client.ObjectClient interface.
+func (a *S3ObjectClient) List(ctx context.Context, prefix, delimiter string) ([]client.StorageObject, []client.StorageCommonPrefix, error) {
+	var storageObjects []client.StorageObject
+	var commonPrefixes []client.StorageCommonPrefix
+
+	for i := range a.bucketNames {
+		input := &s3.ListObjectsV2Input{
+			Bucket:    awssdk.String(a.bucketNames[i]),
+			Prefix:    awssdk.String(prefix),
+			Delimiter: awssdk.String(delimiter),
+		}
+
+		for {
+			output, err := a.s3.ListObjectsV2(ctx, input)
+			if err != nil {
+				return nil, nil, err
+			}
+
+			for _, content := range output.Contents {
+				storageObjects = append(storageObjects, client.StorageObject{
+					Key:        awssdk.ToString(content.Key),
+					ModifiedAt: awssdk.ToTime(content.LastModified),
+				})
+			}
+
+			for _, commonPrefix := range output.CommonPrefixes {
+				commonPrefixes = append(commonPrefixes, client.StorageCommonPrefix(awssdk.ToString(commonPrefix.Prefix)))
+			}
+
+			if output.IsTruncated == nil || !*output.IsTruncated {
+				break
+			}
+			input.ContinuationToken = output.NextContinuationToken
+		}
+	}
+
+	return storageObjects, commonPrefixes, nil
+}
+
+// DeleteObject deletes the specified object key from the configured S3 bucket.
+func (a *S3ObjectClient) DeleteObject(ctx context.Context, objectKey string) error {
+	_, err := a.s3.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: awssdk.String(a.bucket(objectKey)),
+		Key:    awssdk.String(objectKey),
+	})
+	return err
+}
+
+// bucket deterministically picks one of the configured buckets for the given
+// object key, so that chunks and index entries are evenly spread across all
+// of them.
+func (a *S3ObjectClient) bucket(objectKey string) string {
+	if len(a.bucketNames) == 1 {
+		return a.bucketNames[0]
+	}
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(objectKey))
+	return a.bucketNames[h.Sum32()%uint32(len(a.bucketNames))]
+}
+
+// IsObjectNotFoundErr returns true if error means that object is not found. Returns false if err is nil.
+func (a *S3ObjectClient) IsObjectNotFoundErr(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var noSuchKey *types.NoSuchKey
+	if errors.As(err, &noSuchKey) {
+		return true
+	}
+
+	var notFound *types.NotFound
+	if errors.As(err, &notFound) {
+		return true
+	}
+
+	return false
+}
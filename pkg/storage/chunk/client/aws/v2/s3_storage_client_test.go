@@ -0,0 +1,61 @@
+package v2
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/dskit/flagext"
+
+	"github.com/grafana/loki/v3/pkg/storage/chunk/client/aws"
+	"github.com/grafana/loki/v3/pkg/storage/chunk/client/aws/s3test"
+	"github.com/grafana/loki/v3/pkg/storage/chunk/client/hedging"
+)
+
+// TestNew_Conformance runs the same test matrix against both SDK versions,
+// so that the v2 shim's base behavior can't silently drift from the v1
+// client it's meant to be a drop-in replacement for.
+func TestNew_Conformance(t *testing.T) {
+	for _, sdkVersion := range []string{aws.SDKVersionV1, aws.SDKVersionV2} {
+		t.Run(sdkVersion, func(t *testing.T) {
+			server := s3test.NewServer("bucket")
+			defer server.Close()
+
+			c, err := New(aws.S3Config{
+				Endpoint:         server.URL,
+				S3ForcePathStyle: true,
+				Insecure:         true,
+				AccessKeyID:      "key",
+				SecretAccessKey:  flagext.SecretWithValue("secret"),
+				BucketNames:      "bucket",
+				SDKVersion:       sdkVersion,
+			}, hedging.Config{})
+			require.NoError(t, err)
+
+			require.NoError(t, c.PutObject(context.Background(), "foo/bar", strings.NewReader("hello world")))
+
+			rc, size, err := c.GetObject(context.Background(), "foo/bar")
+			require.NoError(t, err)
+			defer rc.Close()
+			body, err := io.ReadAll(rc)
+			require.NoError(t, err)
+			require.Equal(t, "hello world", string(body))
+			require.Equal(t, int64(len(body)), size)
+
+			objects, commonPrefixes, err := c.List(context.Background(), "", "")
+			require.NoError(t, err)
+			require.Empty(t, commonPrefixes)
+			require.Len(t, objects, 1)
+			require.Equal(t, "foo/bar", objects[0].Key)
+
+			require.NoError(t, c.DeleteObject(context.Background(), "foo/bar"))
+
+			_, _, err = c.GetObject(context.Background(), "foo/bar")
+			require.Error(t, err)
+			require.True(t, c.IsObjectNotFoundErr(err))
+		})
+	}
+}